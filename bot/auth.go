@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CommandAuthorizer decides whether the caller identified by adapterIdentity is allowed to
+// execute cmd. Adapters report the identity of the chat user (Slack user id, Telegram chat
+// id, etc.) that issued the command; the server consults the configured CommandAuthorizer
+// before executing it.
+type CommandAuthorizer interface {
+	Authorize(cmd Command, adapterIdentity Identity) error
+}
+
+// allowAllAuthorizer authorizes every command. It exists to preserve the bot server's
+// historical, unauthenticated behavior for installations that have not configured
+// per-recipient access control.
+type allowAllAuthorizer struct{}
+
+// NewAllowAllAuthorizer returns a CommandAuthorizer that allows every command regardless of
+// caller identity. Use it to retain the bot server's pre-RBAC behavior.
+func NewAllowAllAuthorizer() CommandAuthorizer {
+	return &allowAllAuthorizer{}
+}
+
+func (a *allowAllAuthorizer) Authorize(cmd Command, adapterIdentity Identity) error {
+	return nil
+}
+
+// accessRule is the ConfigMap value describing what a single chat identity is allowed to
+// do: the recipient strings it may subscribe/unsubscribe, and the Application/AppProject
+// name globs it may target.
+type accessRule struct {
+	Recipients []string `json:"recipients"`
+	Projects   []string `json:"projects"`
+}
+
+// configMapAuthorizer authorizes commands using a ConfigMap that maps a chat identity
+// ("<adapter>:<id>", e.g. "slack:U12345") to the accessRule describing what that identity
+// may manage.
+type configMapAuthorizer struct {
+	k8sClient kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapAuthorizer returns a CommandAuthorizer backed by the ConfigMap name in
+// namespace. Each entry key must be "<adapter>:<id>" (matching Identity.Adapter and
+// Identity.ID) and the value a JSON accessRule, e.g.:
+//
+//	slack:U12345: |
+//	  {"recipients": ["slack:U12345"], "projects": ["default", "payments-*"]}
+func NewConfigMapAuthorizer(k8sClient kubernetes.Interface, namespace string, name string) CommandAuthorizer {
+	return &configMapAuthorizer{k8sClient: k8sClient, namespace: namespace, name: name}
+}
+
+func (a *configMapAuthorizer) Authorize(cmd Command, adapterIdentity Identity) error {
+	cm, err := a.k8sClient.CoreV1().ConfigMaps(a.namespace).Get(a.name, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s:%s", adapterIdentity.Adapter, adapterIdentity.ID)
+	raw, ok := cm.Data[key]
+	if !ok {
+		return fmt.Errorf("%s is not authorized to execute bot commands", key)
+	}
+	var rule accessRule
+	if err := json.Unmarshal([]byte(raw), &rule); err != nil {
+		return fmt.Errorf("invalid access rule for %s: %v", key, err)
+	}
+	if cmd.Recipient != "" && !matchesAny(rule.Recipients, cmd.Recipient) {
+		return fmt.Errorf("%s is not allowed to manage recipient %s", key, cmd.Recipient)
+	}
+	target, kind, bulk := subscriptionTarget(cmd)
+	switch {
+	case bulk:
+		if findStringIndex(rule.Projects, "*") < 0 {
+			return fmt.Errorf("%s is not allowed to bulk-manage %ss", key, kind)
+		}
+	case target != "":
+		if !matchesAny(rule.Projects, target) {
+			return fmt.Errorf("%s is not allowed to manage %s %s", key, kind, target)
+		}
+	}
+	return nil
+}
+
+// subscriptionTarget returns the Application/AppProject name the command targets, along
+// with a human readable kind ("application" or "project"). bulk is true when the command
+// uses a label Selector instead of naming a single resource, in which case target is "" and
+// only a literal "*" entry in an accessRule's Projects authorizes the command. It covers
+// every command variant that names or lists a single Application/AppProject -
+// Subscribe/Unsubscribe, ListSubscribers and TestNotification - so none of them can bypass
+// the project-level check.
+func subscriptionTarget(cmd Command) (target string, kind string, bulk bool) {
+	switch {
+	case cmd.Subscribe != nil:
+		return updateSubscriptionTarget(cmd.Subscribe)
+	case cmd.Unsubscribe != nil:
+		return updateSubscriptionTarget(cmd.Unsubscribe)
+	case cmd.ListSubscribers != nil:
+		name, kind := appOrProjectTarget(cmd.ListSubscribers.App, cmd.ListSubscribers.Project)
+		return name, kind, false
+	case cmd.TestNotification != nil:
+		return cmd.TestNotification.App, "application", false
+	default:
+		return "", "", false
+	}
+}
+
+func updateSubscriptionTarget(opts *UpdateSubscription) (target string, kind string, bulk bool) {
+	name, kind := appOrProjectTarget(opts.App, opts.Project)
+	if opts.Selector != "" {
+		return "", kind, true
+	}
+	return name, kind, false
+}
+
+// appOrProjectTarget picks whichever of app/project is set, along with a human readable
+// kind ("application" or "project").
+func appOrProjectTarget(app string, project string) (name string, kind string) {
+	if app != "" {
+		return app, "application"
+	}
+	return project, "project"
+}
+
+// matchesAny reports whether name matches any of the glob patterns, using the same
+// semantics as path.Match (e.g. "payments-*").
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testAuthConfigMapName = "argocd-notifications-bot-access"
+
+func newTestAuthorizer(t *testing.T, rules map[string]accessRule) CommandAuthorizer {
+	t.Helper()
+	data := map[string]string{}
+	for key, rule := range rules {
+		raw, err := json.Marshal(rule)
+		require.NoError(t, err)
+		data[key] = string(raw)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: testAuthConfigMapName, Namespace: "default"},
+		Data:       data,
+	}
+	client := k8sfake.NewSimpleClientset(cm)
+	return NewConfigMapAuthorizer(client, "default", testAuthConfigMapName)
+}
+
+func TestConfigMapAuthorizer_UnknownIdentityIsDenied(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{})
+	err := a.Authorize(Command{Recipient: "slack:me", ListSubscriptions: &ListSubscriptions{}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+}
+
+func TestConfigMapAuthorizer_RecipientMismatchIsDenied(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"default"}},
+	})
+	err := a.Authorize(Command{Recipient: "slack:someone-else", ListSubscriptions: &ListSubscriptions{}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+}
+
+func TestConfigMapAuthorizer_RecipientMatchIsAllowed(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"default"}},
+	})
+	err := a.Authorize(Command{Recipient: "slack:U1", ListSubscriptions: &ListSubscriptions{}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.NoError(t, err)
+}
+
+func TestConfigMapAuthorizer_ProjectGlobMismatchIsDenied(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"payments-*"}},
+	})
+	err := a.Authorize(Command{
+		Recipient: "slack:U1",
+		Subscribe: &UpdateSubscription{App: "checkout"},
+	}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+}
+
+func TestConfigMapAuthorizer_ProjectGlobMatchIsAllowed(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"payments-*"}},
+	})
+	err := a.Authorize(Command{
+		Recipient: "slack:U1",
+		Subscribe: &UpdateSubscription{App: "payments-api"},
+	}, Identity{Adapter: "slack", ID: "U1"})
+	assert.NoError(t, err)
+}
+
+func TestConfigMapAuthorizer_BulkSelectorRequiresWildcardProject(t *testing.T) {
+	scoped := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"payments-*"}},
+	})
+	err := scoped.Authorize(Command{
+		Recipient: "slack:U1",
+		Subscribe: &UpdateSubscription{App: "anything", Selector: "team=payments"},
+	}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+
+	wildcard := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"*"}},
+	})
+	err = wildcard.Authorize(Command{
+		Recipient: "slack:U1",
+		Subscribe: &UpdateSubscription{App: "anything", Selector: "team=payments"},
+	}, Identity{Adapter: "slack", ID: "U1"})
+	assert.NoError(t, err)
+}
+
+func TestConfigMapAuthorizer_ListSubscribersAndTestNotificationRespectProjectGlob(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]accessRule{
+		"slack:U1": {Recipients: []string{"slack:U1"}, Projects: []string{"payments-*"}},
+	})
+
+	err := a.Authorize(Command{ListSubscribers: &ListSubscribers{Project: "other-team"}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+
+	err = a.Authorize(Command{TestNotification: &TestNotification{App: "unrelated-app", Trigger: "on-sync-succeeded"}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.Error(t, err)
+
+	err = a.Authorize(Command{TestNotification: &TestNotification{App: "payments-api", Trigger: "on-sync-succeeded"}}, Identity{Adapter: "slack", ID: "U1"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	triggerKeyPrefix  = "trigger."
+	templateKeyPrefix = "template."
+)
+
+// NotificationsConfig provides read access to the notifications ConfigMap: the configured
+// triggers and the templates they send, so the bot can list them and render a one-off
+// notification on demand.
+type NotificationsConfig interface {
+	// Triggers returns the configured trigger names mapped to their description.
+	Triggers() (map[string]string, error)
+	// Render renders every template the named trigger sends against app, returning the
+	// combined notification body.
+	Render(app string, trigger string) (string, error)
+}
+
+type triggerDefinition struct {
+	Description string   `yaml:"description"`
+	Send        []string `yaml:"send"`
+}
+
+type templateDefinition struct {
+	Message string `yaml:"message"`
+}
+
+// notificationsConfig is the default NotificationsConfig, backed by a ConfigMap of trigger
+// and template definitions and the Application client used to render them.
+type notificationsConfig struct {
+	k8sClient kubernetes.Interface
+	namespace string
+	name      string
+	appClient dynamic.ResourceInterface
+}
+
+// NewNotificationsConfig returns a NotificationsConfig backed by the ConfigMap name in
+// namespace. Trigger definitions are stored under keys "trigger.<name>" and templates under
+// "template.<name>", both YAML encoded.
+func NewNotificationsConfig(k8sClient kubernetes.Interface, namespace string, name string, appClient dynamic.ResourceInterface) NotificationsConfig {
+	return &notificationsConfig{k8sClient: k8sClient, namespace: namespace, name: name, appClient: appClient}
+}
+
+func (c *notificationsConfig) configMap() (map[string]string, error) {
+	cm, err := c.k8sClient.CoreV1().ConfigMaps(c.namespace).Get(c.name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return cm.Data, nil
+}
+
+func (c *notificationsConfig) Triggers() (map[string]string, error) {
+	data, err := c.configMap()
+	if err != nil {
+		return nil, err
+	}
+	triggers := map[string]string{}
+	for key, val := range data {
+		name := strings.TrimPrefix(key, triggerKeyPrefix)
+		if name == key {
+			continue
+		}
+		var def triggerDefinition
+		if err := yaml.Unmarshal([]byte(val), &def); err != nil {
+			return nil, fmt.Errorf("invalid trigger %s: %v", name, err)
+		}
+		triggers[name] = def.Description
+	}
+	return triggers, nil
+}
+
+func (c *notificationsConfig) Render(app string, trigger string) (string, error) {
+	data, err := c.configMap()
+	if err != nil {
+		return "", err
+	}
+	rawTrigger, ok := data[triggerKeyPrefix+trigger]
+	if !ok {
+		return "", fmt.Errorf("trigger %s is not configured", trigger)
+	}
+	var triggerDef triggerDefinition
+	if err := yaml.Unmarshal([]byte(rawTrigger), &triggerDef); err != nil {
+		return "", fmt.Errorf("invalid trigger %s: %v", trigger, err)
+	}
+	if len(triggerDef.Send) == 0 {
+		return "", fmt.Errorf("trigger %s has no templates configured", trigger)
+	}
+	obj, err := c.appClient.Get(app, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	rendered := make([]string, 0, len(triggerDef.Send))
+	for _, templateName := range triggerDef.Send {
+		rawTemplate, ok := data[templateKeyPrefix+templateName]
+		if !ok {
+			return "", fmt.Errorf("template %s is not configured", templateName)
+		}
+		var templateDef templateDefinition
+		if err := yaml.Unmarshal([]byte(rawTemplate), &templateDef); err != nil {
+			return "", fmt.Errorf("invalid template %s: %v", templateName, err)
+		}
+		tmpl, err := template.New(templateName).Parse(templateDef.Message)
+		if err != nil {
+			return "", fmt.Errorf("invalid template %s: %v", templateName, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, obj.Object); err != nil {
+			return "", fmt.Errorf("cannot render template %s: %v", templateName, err)
+		}
+		rendered = append(rendered, buf.String())
+	}
+	return strings.Join(rendered, "\n---\n"), nil
+}
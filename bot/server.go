@@ -1,11 +1,16 @@
 package bot
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
 	"github.com/argoproj-labs/argocd-notifications/shared/recipients"
@@ -13,25 +18,79 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// defaultReadHeaderTimeout and defaultWriteTimeout are used whenever ServerOptions
+	// leaves the corresponding field at its zero value.
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
 )
 
 type Server interface {
 	Serve(port int) error
 	AddAdapter(path string, adapter Adapter)
+	// Shutdown gracefully stops the HTTP server: it stops accepting new connections, then
+	// waits for outstanding command handlers to finish (each may have in-flight Kubernetes
+	// patches that shouldn't be interrupted mid-flight) or for ctx to be done.
+	Shutdown(ctx context.Context) error
+}
+
+// ServerOptions configures the HTTP transport NewServer builds. A zero value serves plain
+// HTTP with the default timeouts.
+type ServerOptions struct {
+	// TLSConfig, if non-nil, makes Serve terminate TLS using it.
+	TLSConfig *tls.Config
+	// ReadHeaderTimeout bounds how long a client may take sending request headers, so a
+	// slow or malicious webhook caller can't tie up a connection indefinitely (Slowloris).
+	// Defaults to defaultReadHeaderTimeout.
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout bounds how long a command handler - including any Kubernetes patches it
+	// issues - may take to write its response. Defaults to defaultWriteTimeout; installations
+	// with large fleets and bulk selector-based subscriptions may need to raise it.
+	WriteTimeout time.Duration
 }
 
-func NewServer(dynamicClient dynamic.Interface, namespace string) *server {
+// NewServer constructs a bot Server. authorizer is consulted before every command except
+// Help is executed; pass NewAllowAllAuthorizer() to preserve the server's pre-RBAC behavior.
+// notificationsConfig backs the ListTriggers and TestNotification commands.
+func NewServer(dynamicClient dynamic.Interface, namespace string, authorizer CommandAuthorizer, notificationsConfig NotificationsConfig, opts ServerOptions) *server {
+	mux := http.NewServeMux()
+	readHeaderTimeout := opts.ReadHeaderTimeout
+	if readHeaderTimeout <= 0 {
+		readHeaderTimeout = defaultReadHeaderTimeout
+	}
+	writeTimeout := opts.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = defaultWriteTimeout
+	}
 	return &server{
-		mux:           http.NewServeMux(),
-		appClient:     k8s.NewAppClient(dynamicClient, namespace),
-		appProjClient: k8s.NewAppProjClient(dynamicClient, namespace),
+		mux:                 mux,
+		appClient:           k8s.NewAppClient(dynamicClient, namespace),
+		appProjClient:       k8s.NewAppProjClient(dynamicClient, namespace),
+		authorizer:          authorizer,
+		notificationsConfig: notificationsConfig,
+		tlsConfig:           opts.TLSConfig,
+		httpServer: &http.Server{
+			Handler:           mux,
+			TLSConfig:         opts.TLSConfig,
+			ReadHeaderTimeout: readHeaderTimeout,
+			WriteTimeout:      writeTimeout,
+		},
 	}
 }
 
 type server struct {
-	appClient     dynamic.ResourceInterface
-	appProjClient dynamic.ResourceInterface
-	mux           *http.ServeMux
+	appClient           dynamic.ResourceInterface
+	appProjClient       dynamic.ResourceInterface
+	mux                 *http.ServeMux
+	authorizer          CommandAuthorizer
+	notificationsConfig NotificationsConfig
+	tlsConfig           *tls.Config
+
+	httpServer *http.Server
+	inFlight   sync.WaitGroup
 }
 
 func copyStringMap(in map[string]string) map[string]string {
@@ -44,11 +103,24 @@ func copyStringMap(in map[string]string) map[string]string {
 
 func (s *server) handler(adapter Adapter) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cmd, err := adapter.Parse(r)
+		s.inFlight.Add(1)
+		defer s.inFlight.Done()
+		cmd, identity, err := adapter.Parse(r)
 		if err != nil {
 			adapter.SendResponse(err.Error(), w)
 			return
 		}
+		// Help is exempt from authorization: it only echoes the adapter's static command
+		// syntax, so there is nothing to protect, and callers the operator hasn't gotten
+		// around to adding to the access-control ConfigMap yet still need to see it.
+		if cmd.Help != nil {
+			adapter.SendResponse(adapter.Syntax(), w)
+			return
+		}
+		if err := s.authorizer.Authorize(cmd, identity); err != nil {
+			adapter.SendResponse(fmt.Sprintf("command rejected: %v", err), w)
+			return
+		}
 		if res, err := s.execute(cmd); err != nil {
 			adapter.SendResponse(fmt.Sprintf("cannot execute command: %v", err), w)
 		} else {
@@ -65,11 +137,91 @@ func (s *server) execute(cmd Command) (string, error) {
 		return s.updateSubscription(cmd.Recipient, true, *cmd.Subscribe)
 	case cmd.Unsubscribe != nil:
 		return s.updateSubscription(cmd.Recipient, false, *cmd.Unsubscribe)
+	case cmd.ListTriggers != nil:
+		return s.listTriggers()
+	case cmd.TestNotification != nil:
+		return s.testNotification(*cmd.TestNotification)
+	case cmd.ListSubscribers != nil:
+		return s.listSubscribers(*cmd.ListSubscribers)
 	default:
 		return "", errors.New("unknown command")
 	}
 }
 
+func (s *server) listTriggers() (string, error) {
+	triggers, err := s.notificationsConfig.Triggers()
+	if err != nil {
+		return "", err
+	}
+	if len(triggers) == 0 {
+		return "No triggers are configured.", nil
+	}
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		if desc := triggers[name]; desc != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, desc))
+		} else {
+			lines = append(lines, name)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *server) testNotification(opts TestNotification) (string, error) {
+	if opts.App == "" || opts.Trigger == "" {
+		return "", errors.New("both application and trigger name must be specified")
+	}
+	return s.notificationsConfig.Render(opts.App, opts.Trigger)
+}
+
+func (s *server) listSubscribers(opts ListSubscribers) (string, error) {
+	var name string
+	var client dynamic.ResourceInterface
+	switch {
+	case opts.App != "":
+		name = opts.App
+		client = s.appClient
+	case opts.Project != "":
+		name = opts.Project
+		client = s.appProjClient
+	default:
+		return "", errors.New("either application or project name must be specified")
+	}
+	obj, err := client.Get(name, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	type subscribers struct {
+		trigger    string
+		recipients []string
+	}
+	var groups []subscribers
+	for key, val := range obj.GetAnnotations() {
+		if !strings.HasSuffix(key, recipients.AnnotationKey) {
+			continue
+		}
+		trigger := strings.TrimSuffix(key, "."+recipients.AnnotationKey)
+		if trigger == key {
+			trigger = "default"
+		}
+		groups = append(groups, subscribers{trigger: trigger, recipients: recipients.ParseRecipients(val)})
+	}
+	if len(groups) == 0 {
+		return fmt.Sprintf("%s has no subscribers.", name), nil
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].trigger < groups[j].trigger })
+	lines := make([]string, 0, len(groups))
+	for _, g := range groups {
+		lines = append(lines, fmt.Sprintf("%s: %s", g.trigger, strings.Join(g.recipients, ", ")))
+	}
+	return fmt.Sprintf("Subscribers of %s:\n%s", name, strings.Join(lines, "\n")), nil
+}
+
 func findStringIndex(items []string, item string) int {
 	for i := range items {
 		if items[i] == item {
@@ -125,6 +277,9 @@ func removeSubscription(recipient string, trigger string, annotations map[string
 }
 
 func (s *server) updateSubscription(recipient string, subscribe bool, opts UpdateSubscription) (string, error) {
+	if opts.Selector != "" {
+		return s.bulkUpdateSubscription(recipient, subscribe, opts)
+	}
 	var name string
 	var client dynamic.ResourceInterface
 	switch {
@@ -137,35 +292,144 @@ func (s *server) updateSubscription(recipient string, subscribe bool, opts Updat
 	default:
 		return "", errors.New("either application or project name must be specified")
 	}
-	obj, err := client.Get(name, v1.GetOptions{})
+	if err := patchSubscription(client, name, recipient, opts.Trigger, subscribe); err != nil {
+		return "", err
+	}
+	return "subscription updated", nil
+}
+
+const (
+	// maxBulkSubscriptionResponseLen keeps bulk subscription responses within the message
+	// size most chat adapters allow; a truncated response still ends with a summary line.
+	maxBulkSubscriptionResponseLen = 3900
+	// maxBulkSubscriptionItems bounds how many objects a single selector-based command will
+	// touch, so a selector matching a large fleet can't run past the server's WriteTimeout
+	// with nobody left to observe the outcome. Anything beyond this is left untouched; the
+	// response says so rather than silently dropping it.
+	maxBulkSubscriptionItems = 500
+	// maxBulkSubscriptionConcurrency caps how many patches bulkUpdateSubscription issues at
+	// once, trading off staying well within WriteTimeout against overwhelming the API server.
+	maxBulkSubscriptionConcurrency = 10
+)
+
+// bulkUpdateSubscription subscribes/unsubscribes recipient to every Application or
+// AppProject matched by opts.Selector, aggregating per-object success/failure into a single
+// response. Items are patched concurrently (bounded by maxBulkSubscriptionConcurrency) so a
+// large match doesn't serialize one slow retry-on-conflict loop after another.
+func (s *server) bulkUpdateSubscription(recipient string, subscribe bool, opts UpdateSubscription) (string, error) {
+	var client dynamic.ResourceInterface
+	var kind string
+	switch {
+	case opts.App != "":
+		client = s.appClient
+		kind = "application"
+	case opts.Project != "":
+		client = s.appProjClient
+		kind = "project"
+	default:
+		return "", errors.New("either application or project must be specified alongside a selector")
+	}
+	list, err := client.List(v1.ListOptions{LabelSelector: opts.Selector})
 	if err != nil {
 		return "", err
 	}
-	oldAnnotations := copyStringMap(obj.GetAnnotations())
-	var newAnnotations map[string]string
-	if subscribe {
-		newAnnotations = addSubscription(recipient, opts.Trigger, obj.GetAnnotations())
-	} else {
-		newAnnotations = removeSubscription(recipient, opts.Trigger, obj.GetAnnotations())
+	if len(list.Items) == 0 {
+		return fmt.Sprintf("no %ss matched selector %q", kind, opts.Selector), nil
 	}
-	annotationsPatch := annotationsPatch(oldAnnotations, newAnnotations)
-	if len(annotationsPatch) > 0 {
-		patch := map[string]map[string]interface{}{
-			"metadata": {
-				"annotations": annotationsPatch,
-			},
+	items := list.Items
+	skipped := 0
+	if len(items) > maxBulkSubscriptionItems {
+		skipped = len(items) - maxBulkSubscriptionItems
+		items = items[:maxBulkSubscriptionItems]
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make([]result, len(items))
+	sem := make(chan struct{}, maxBulkSubscriptionConcurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, objName string, displayName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = result{name: displayName, err: patchSubscription(client, objName, recipient, opts.Trigger, subscribe)}
+		}(i, item.GetName(), fmt.Sprintf("%s/%s", item.GetNamespace(), item.GetName()))
+	}
+	wg.Wait()
+
+	var succeeded, failed []string
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", r.name, r.err))
+		} else {
+			succeeded = append(succeeded, r.name)
 		}
-		patchData, err := json.Marshal(patch)
+	}
+	action := "subscribed to"
+	if !subscribe {
+		action = "unsubscribed from"
+	}
+	summary := fmt.Sprintf("%s %d %ss, %d failed", action, len(succeeded), kind, len(failed))
+	if skipped > 0 {
+		summary = fmt.Sprintf("%s (selector matched %d more %ss that were not processed; refine the selector)", summary, skipped, kind)
+	}
+	if len(failed) == 0 {
+		return summary, nil
+	}
+	return truncateResponse(fmt.Sprintf("%s:\n%s", summary, strings.Join(failed, "\n")), summary), nil
+}
+
+// truncateResponse keeps response within maxBulkSubscriptionResponseLen, replacing any
+// cut-off tail with a short summary so the caller still learns the overall outcome.
+func truncateResponse(response string, summary string) string {
+	if len(response) <= maxBulkSubscriptionResponseLen {
+		return response
+	}
+	tail := fmt.Sprintf("\n... truncated (%s)", summary)
+	cut := maxBulkSubscriptionResponseLen - len(tail)
+	if cut < 0 {
+		cut = 0
+	}
+	return response[:cut] + tail
+}
+
+// patchSubscription adds or removes recipient from name's subscription annotations. The
+// patch is computed from a fresh Get of the object and retried on a 409 Conflict (e.g. a
+// concurrent subscribe/unsubscribe from another caller, or a racing reconcile), so a stale
+// read can never silently clobber someone else's write.
+func patchSubscription(client dynamic.ResourceInterface, name string, recipient string, trigger string, subscribe bool) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		obj, err := client.Get(name, v1.GetOptions{})
 		if err != nil {
-			return "", err
+			return err
 		}
-		_, err = client.Patch(name, types.MergePatchType, patchData, v1.PatchOptions{})
+		oldAnnotations := copyStringMap(obj.GetAnnotations())
+		var newAnnotations map[string]string
+		if subscribe {
+			newAnnotations = addSubscription(recipient, trigger, obj.GetAnnotations())
+		} else {
+			newAnnotations = removeSubscription(recipient, trigger, obj.GetAnnotations())
+		}
+		patch := annotationsPatch(oldAnnotations, newAnnotations)
+		if len(patch) == 0 {
+			return nil
+		}
+		patchData, err := json.Marshal(map[string]map[string]interface{}{
+			"metadata": {
+				"annotations":     patch,
+				"resourceVersion": obj.GetResourceVersion(),
+			},
+		})
 		if err != nil {
-			return "", err
+			return err
 		}
-	}
-
-	return "subscription updated", nil
+		_, err = client.Patch(name, types.MergePatchType, patchData, v1.PatchOptions{})
+		return err
+	})
 }
 
 func (s *server) listSubscriptions(recipient string) (string, error) {
@@ -222,5 +486,32 @@ func (s *server) AddAdapter(pattern string, adapter Adapter) {
 }
 
 func (s *server) Serve(port int) error {
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), s.mux)
+	s.httpServer.Addr = fmt.Sprintf(":%d", port)
+	if s.tlsConfig != nil {
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown may run concurrently with Serve (e.g. from a signal handler while Serve blocks in
+// its own goroutine). s.httpServer is constructed once in NewServer, so it is never nil and
+// never reassigned - there is nothing left for the two to race on. http.Server also tracks
+// its own shutdown state, so a Shutdown that completes before Serve calls ListenAndServe (or
+// ListenAndServeTLS) makes that call return http.ErrServerClosed immediately instead of
+// binding and blocking forever.
+func (s *server) Shutdown(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/recipients"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var fakeAppGVR = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+func newFakeApp() *unstructured.Unstructured {
+	app := &unstructured.Unstructured{}
+	app.SetUnstructuredContent(map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+	})
+	app.SetName("guestbook")
+	app.SetNamespace("default")
+	return app
+}
+
+// TestPatchSubscription_RetriesOnConflict simulates another caller's subscription landing
+// in between our Get and Patch: the first Patch attempt is rejected with a Conflict, and by
+// the time we retry, the object already carries the concurrent subscriber's annotation. Both
+// subscriptions must survive.
+func TestPatchSubscription_RetriesOnConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{fakeAppGVR: "ApplicationList"}, newFakeApp())
+	appClient := client.Resource(fakeAppGVR).Namespace("default")
+
+	conflicted := false
+	client.PrependReactor("patch", "applications", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+
+		concurrent, err := appClient.Get("guestbook", metav1.GetOptions{})
+		require.NoError(t, err)
+		concurrent.SetAnnotations(addSubscription("slack:other", "", concurrent.GetAnnotations()))
+		_, err = appClient.Update(concurrent, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		return true, nil, apierrors.NewConflict(fakeAppGVR.GroupResource(), "guestbook", errors.New("concurrent modification"))
+	})
+
+	err := patchSubscription(appClient, "guestbook", "slack:me", "", true)
+	require.NoError(t, err)
+
+	got, err := appClient.Get("guestbook", metav1.GetOptions{})
+	require.NoError(t, err)
+	subscribed := recipients.ParseRecipients(got.GetAnnotations()[recipients.AnnotationKey])
+	assert.ElementsMatch(t, []string{"slack:other", "slack:me"}, subscribed)
+}
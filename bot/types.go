@@ -0,0 +1,81 @@
+package bot
+
+import "net/http"
+
+// Command represents a single chat command parsed by an Adapter from an inbound webhook
+// request. Exactly one of ListSubscriptions, Subscribe, Unsubscribe, Help, ListTriggers,
+// TestNotification or ListSubscribers should be set.
+type Command struct {
+	// Recipient is the destination (e.g. "slack:general" or "email:a@b.com") the command
+	// subscribes/unsubscribes or whose subscriptions should be listed.
+	Recipient string
+
+	ListSubscriptions *ListSubscriptions
+	Subscribe         *UpdateSubscription
+	Unsubscribe       *UpdateSubscription
+	Help              *Help
+	ListTriggers      *ListTriggers
+	TestNotification  *TestNotification
+	ListSubscribers   *ListSubscribers
+}
+
+// ListSubscriptions requests the set of Applications/AppProjects that Command.Recipient is
+// currently subscribed to.
+type ListSubscriptions struct {
+}
+
+// UpdateSubscription describes a subscribe/unsubscribe request for either an Application
+// (App) or an AppProject (Project), optionally scoped to a single Trigger.
+//
+// Instead of naming a single App/Project, a command can set Selector to a Kubernetes label
+// selector (e.g. "team=payments,env!=dev"); App or Project is still used to say which kind
+// of resource the selector applies to, but its value is otherwise ignored.
+type UpdateSubscription struct {
+	App      string
+	Project  string
+	Trigger  string
+	Selector string
+}
+
+// Help requests the command syntax the active Adapter accepts.
+type Help struct {
+}
+
+// ListTriggers requests the names and descriptions of every configured notification
+// trigger.
+type ListTriggers struct {
+}
+
+// TestNotification renders the template App/Trigger would send and delivers it to the
+// caller, without persisting a subscription.
+type TestNotification struct {
+	App     string
+	Trigger string
+}
+
+// ListSubscribers is the inverse of ListSubscriptions: it lists every recipient currently
+// subscribed to a single Application (App) or AppProject (Project), grouped by trigger.
+type ListSubscribers struct {
+	App     string
+	Project string
+}
+
+// Identity identifies the chat user or channel that issued a Command, as reported by the
+// Adapter that parsed it (e.g. a Slack user id or a Telegram chat id).
+type Identity struct {
+	// Adapter is the name of the adapter that produced this identity, e.g. "slack".
+	Adapter string
+	// ID is the adapter specific identity of the caller.
+	ID string
+}
+
+// Adapter integrates a chat platform with the notifications bot server. It parses the
+// platform specific webhook payload into a Command plus the Identity of the caller, and
+// delivers the response back using whatever mechanism the platform expects.
+type Adapter interface {
+	Parse(r *http.Request) (Command, Identity, error)
+	SendResponse(response string, w http.ResponseWriter)
+	// Syntax returns the adapter specific command syntax, sent back in response to a Help
+	// command.
+	Syntax() string
+}